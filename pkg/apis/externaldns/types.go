@@ -30,81 +30,93 @@ var (
 
 // Config is a project-wide configuration
 type Config struct {
-	Master               string
-	KubeConfig           string
-	Sources              []string
-	Namespace            string
-	AnnotationFilter     string
-	FQDNTemplate         string
-	Compatibility        string
-	PublishInternal      bool
-	Provider             string
-	GoogleProject        string
-	DomainFilter         []string
-	BaseDomainFilter     []string
-	CidrIgnore           []string
-	DNSIgnore            []string
-	AWSZoneType          string
-	AzureConfigFile      string
-	AzureResourceGroup   string
-	CloudflareProxied    bool
-	InfobloxGridHost     string
-	InfobloxWapiPort     int
-	InfobloxWapiUsername string
-	InfobloxWapiPassword string
-	InfobloxWapiVersion  string
-	InfobloxSSLVerify    bool
-	InMemoryZones        []string
-	Policy               string
-	Registry             string
-	TXTOwnerID           string
-	TXTPrefix            string
-	Interval             time.Duration
-	Once                 bool
-	DryRun               bool
-	Cleanup              bool
-	LogFormat            string
-	MetricsAddress       string
-	LogLevel             string
+	Master                string
+	KubeConfig            string
+	Sources               []string
+	Namespace             string
+	AnnotationFilter      string
+	FQDNTemplate          string
+	Compatibility         string
+	PublishInternal       bool
+	Provider              string
+	GoogleProject         string
+	DomainFilter          []string
+	BaseDomainFilter      []string
+	CidrIgnore            []string
+	DNSIgnore             []string
+	FilterPolicy          string
+	CidrAllow             []string
+	DNSAllow              []string
+	AWSZoneType           string
+	AzureConfigFile       string
+	AzureResourceGroup    string
+	CloudflareProxied     bool
+	InfobloxGridHost      string
+	InfobloxWapiPort      int
+	InfobloxWapiUsername  string
+	InfobloxWapiPassword  string
+	InfobloxWapiVersion   string
+	InfobloxSSLVerify     bool
+	InMemoryZones         []string
+	NameserverBindAddress string
+	NameserverDefaultTTL  time.Duration
+	Policy                string
+	Registry              string
+	TXTOwnerID            string
+	TXTPrefix             string
+	Interval              time.Duration
+	Once                  bool
+	DryRun                bool
+	Cleanup               bool
+	LogFormat             string
+	MetricsAddress        string
+	DoHAddress            string
+	LogLevel              string
 }
 
 var defaultConfig = &Config{
-	Master:               "",
-	KubeConfig:           "",
-	Sources:              nil,
-	Namespace:            "",
-	AnnotationFilter:     "",
-	FQDNTemplate:         "",
-	Compatibility:        "",
-	PublishInternal:      false,
-	Provider:             "",
-	GoogleProject:        "",
-	DomainFilter:         []string{},
-	BaseDomainFilter:     []string{},
-	CidrIgnore:           []string{},
-	DNSIgnore:            []string{},
-	AWSZoneType:          "",
-	AzureConfigFile:      "/etc/kubernetes/azure.json",
-	AzureResourceGroup:   "",
-	CloudflareProxied:    false,
-	InfobloxGridHost:     "",
-	InfobloxWapiPort:     443,
-	InfobloxWapiUsername: "admin",
-	InfobloxWapiPassword: "",
-	InfobloxWapiVersion:  "2.3.1",
-	InfobloxSSLVerify:    true,
-	InMemoryZones:        []string{},
-	Policy:               "sync",
-	Registry:             "txt",
-	TXTOwnerID:           "default",
-	TXTPrefix:            "",
-	Interval:             time.Minute,
-	Once:                 false,
-	DryRun:               false,
-	Cleanup:              false,
-	LogFormat:            "text",
-	MetricsAddress:       ":7979",
-	LogLevel:             logrus.InfoLevel.String(),
+	Master:                "",
+	KubeConfig:            "",
+	Sources:               nil,
+	Namespace:             "",
+	AnnotationFilter:      "",
+	FQDNTemplate:          "",
+	Compatibility:         "",
+	PublishInternal:       false,
+	Provider:              "",
+	GoogleProject:         "",
+	DomainFilter:          []string{},
+	BaseDomainFilter:      []string{},
+	CidrIgnore:            []string{},
+	DNSIgnore:             []string{},
+	FilterPolicy:          "accept",
+	CidrAllow:             []string{},
+	DNSAllow:              []string{},
+	AWSZoneType:           "",
+	AzureConfigFile:       "/etc/kubernetes/azure.json",
+	AzureResourceGroup:    "",
+	CloudflareProxied:     false,
+	InfobloxGridHost:      "",
+	InfobloxWapiPort:      443,
+	InfobloxWapiUsername:  "admin",
+	InfobloxWapiPassword:  "",
+	InfobloxWapiVersion:   "2.3.1",
+	InfobloxSSLVerify:     true,
+	InMemoryZones:         []string{},
+	NameserverBindAddress: ":5353",
+	NameserverDefaultTTL:  300 * time.Second,
+	Policy:                "sync",
+	Registry:              "txt",
+	TXTOwnerID:            "default",
+	TXTPrefix:             "",
+	Interval:              time.Minute,
+	Once:                  false,
+	DryRun:                false,
+	Cleanup:               false,
+	LogFormat:             "text",
+	MetricsAddress:        ":7979",
+	DoHAddress:            "",
+	LogLevel:              logrus.InfoLevel.String(),
 }
 
 // NewConfig returns new Config object
@@ -140,11 +152,14 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("publish-internal-services", "Allow external-dns to publish DNS records for ClusterIP services (optional)").BoolVar(&cfg.PublishInternal)
 
 	// Flags related to providers
-	app.Flag("provider", "The DNS provider where the DNS records will be created (required, options: aws, google, azure, cloudflare, digitalocean, dnsimple, infoblox, inmemory)").Required().PlaceHolder("provider").EnumVar(&cfg.Provider, "aws", "google", "azure", "cloudflare", "digitalocean", "dnsimple", "infoblox", "inmemory")
+	app.Flag("provider", "The DNS provider where the DNS records will be created (required, options: aws, google, azure, cloudflare, digitalocean, dnsimple, infoblox, inmemory, nameserver)").Required().PlaceHolder("provider").EnumVar(&cfg.Provider, "aws", "google", "azure", "cloudflare", "digitalocean", "dnsimple", "infoblox", "inmemory", "nameserver")
 	app.Flag("domain-filter", "Limit possible target zones by a domain suffix; specify multiple times for multiple domains (optional)").Default("").StringsVar(&cfg.DomainFilter)
 	app.Flag("basedomain-filter", "Limit possible DNS entries by a domain suffix; specify multiple times for multiple domains (optional)").Default().StringsVar(&cfg.BaseDomainFilter)
 	app.Flag("cidr-ignore", "Limit DNS entries excluding IP addresses in given ranges").StringsVar(&cfg.CidrIgnore)
 	app.Flag("dns-ignore", "Limit DNS entries excluding given DNS (wirldcard) names").StringsVar(&cfg.DNSIgnore)
+	app.Flag("filter-policy", "Whether endpoints not matched by an allow rule are kept by default or dropped by default (default: accept, options: accept, deny)").Default(defaultConfig.FilterPolicy).EnumVar(&cfg.FilterPolicy, "accept", "deny")
+	app.Flag("cidr-allow", "When --filter-policy=deny, let through IP addresses in given ranges regardless of --cidr-ignore").StringsVar(&cfg.CidrAllow)
+	app.Flag("dns-allow", "When --filter-policy=deny, let through given DNS (wildcard) names regardless of --dns-ignore").StringsVar(&cfg.DNSAllow)
 	app.Flag("google-project", "When using the Google provider, specify the Google project (required when --provider=google)").Default(defaultConfig.GoogleProject).StringVar(&cfg.GoogleProject)
 	app.Flag("aws-zone-type", "When using the AWS provider, filter for zones of this type (optional, options: public, private)").Default(defaultConfig.AWSZoneType).EnumVar(&cfg.AWSZoneType, "", "public", "private")
 	app.Flag("azure-config-file", "When using the Azure provider, specify the Azure configuration file (required when --provider=azure").Default(defaultConfig.AzureConfigFile).StringVar(&cfg.AzureConfigFile)
@@ -157,6 +172,8 @@ func (cfg *Config) ParseFlags(args []string) error {
 	app.Flag("infoblox-wapi-version", "When using the Infoblox provider, specify the WAPI version (default: 2.3.1)").Default(defaultConfig.InfobloxWapiVersion).StringVar(&cfg.InfobloxWapiVersion)
 	app.Flag("infoblox-ssl-verify", "When using the Infoblox provider, specify whether to verify the SSL certificate (default: true, disable with --no-infoblox-ssl-verify)").Default(strconv.FormatBool(defaultConfig.InfobloxSSLVerify)).BoolVar(&cfg.InfobloxSSLVerify)
 	app.Flag("inmemory-zone", "Provide a list of pre-configured zones for the inmemory provider; specify multiple times for multiple zones (optional)").Default("").StringsVar(&cfg.InMemoryZones)
+	app.Flag("nameserver-bind-address", "When using the nameserver provider, specify the UDP/TCP address to serve the zones on (default: :5353)").Default(defaultConfig.NameserverBindAddress).StringVar(&cfg.NameserverBindAddress)
+	app.Flag("nameserver-default-ttl", "When using the nameserver provider, specify the TTL to serve for records that don't carry one (default: 5m)").Default(defaultConfig.NameserverDefaultTTL.String()).DurationVar(&cfg.NameserverDefaultTTL)
 
 	// Flags related to policies
 	app.Flag("policy", "Modify how DNS records are sychronized between sources and providers (default: sync, options: sync, upsert-only)").Default(defaultConfig.Policy).EnumVar(&cfg.Policy, "sync", "upsert-only")
@@ -175,6 +192,7 @@ func (cfg *Config) ParseFlags(args []string) error {
 	// Miscellaneous flags
 	app.Flag("log-format", "The format in which log messages are printed (default: text, options: text, json)").Default(defaultConfig.LogFormat).EnumVar(&cfg.LogFormat, "text", "json")
 	app.Flag("metrics-address", "Specify where to serve the metrics and health check endpoint (default: :7979)").Default(defaultConfig.MetricsAddress).StringVar(&cfg.MetricsAddress)
+	app.Flag("doh-address", "Specify where to serve a DNS-over-HTTPS (RFC 8484) endpoint for the managed zones at /dns-query (optional, disabled by default)").Default(defaultConfig.DoHAddress).StringVar(&cfg.DoHAddress)
 	app.Flag("log-level", "Set the level of logging. (default: info, options: panic, debug, info, warn, error, fatal").Default(defaultConfig.LogLevel).EnumVar(&cfg.LogLevel, allLogLevelsAsStrings()...)
 
 	_, err := app.Parse(args)
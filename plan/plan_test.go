@@ -0,0 +1,124 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/kubernetes-incubator/external-dns/endpoint"
+)
+
+func TestCalculate(t *testing.T) {
+	t.Run("Round Robin A Records", testCalculateRoundRobin)
+	t.Run("Add One Target To Many", testCalculateChangeOneTarget)
+	t.Run("Mixed A And AAAA On Same Name", testCalculateMixedTypes)
+}
+
+// testCalculateRoundRobin ensures that a round-robin set of targets for the
+// same name and type is left untouched when nothing has changed.
+func testCalculateRoundRobin(t *testing.T) {
+	current := []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", Target: "1.2.3.4", RecordType: endpoint.RecordTypeA},
+		{DNSName: "foo.example.org", Target: "5.6.7.8", RecordType: endpoint.RecordTypeA},
+	}
+	desired := []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", Target: "5.6.7.8", RecordType: endpoint.RecordTypeA},
+		{DNSName: "foo.example.org", Target: "1.2.3.4", RecordType: endpoint.RecordTypeA},
+	}
+
+	changes := (&Plan{Current: current, Desired: desired}).Calculate().Changes
+
+	validateEntries(t, changes.Create, nil)
+	validateEntries(t, changes.UpdateOld, nil)
+	validateEntries(t, changes.UpdateNew, nil)
+	validateEntries(t, changes.Delete, nil)
+}
+
+// testCalculateChangeOneTarget ensures that adding a single target to a
+// multi-target rrset produces a paired update for the whole rrset, keeping
+// the untouched siblings.
+func testCalculateChangeOneTarget(t *testing.T) {
+	current := []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", Target: "1.2.3.4", RecordType: endpoint.RecordTypeA},
+		{DNSName: "foo.example.org", Target: "5.6.7.8", RecordType: endpoint.RecordTypeA},
+	}
+	desired := []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", Target: "1.2.3.4", RecordType: endpoint.RecordTypeA},
+		{DNSName: "foo.example.org", Target: "5.6.7.8", RecordType: endpoint.RecordTypeA},
+		{DNSName: "foo.example.org", Target: "9.9.9.9", RecordType: endpoint.RecordTypeA},
+	}
+
+	changes := (&Plan{Current: current, Desired: desired}).Calculate().Changes
+
+	validateEntries(t, changes.Create, nil)
+	validateEntries(t, changes.Delete, nil)
+	validateEntries(t, changes.UpdateOld, current)
+	validateEntries(t, changes.UpdateNew, desired)
+}
+
+// testCalculateMixedTypes ensures that A and AAAA endpoints sharing a DNS
+// name are planned as independent rrsets.
+func testCalculateMixedTypes(t *testing.T) {
+	current := []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", Target: "1.2.3.4", RecordType: endpoint.RecordTypeA},
+		{DNSName: "foo.example.org", Target: "::1", RecordType: endpoint.RecordTypeAAAA},
+	}
+	desired := []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", Target: "1.2.3.4", RecordType: endpoint.RecordTypeA},
+	}
+
+	changes := (&Plan{Current: current, Desired: desired}).Calculate().Changes
+
+	validateEntries(t, changes.Create, nil)
+	validateEntries(t, changes.UpdateOld, nil)
+	validateEntries(t, changes.UpdateNew, nil)
+	validateEntries(t, changes.Delete, []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", Target: "::1", RecordType: endpoint.RecordTypeAAAA},
+	})
+}
+
+// validateEntries compares a list of endpoints against the expected one,
+// ignoring order.
+func validateEntries(t *testing.T, entries, expected []*endpoint.Endpoint) {
+	t.Helper()
+
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d: %v", len(expected), len(entries), entries)
+	}
+
+	sortEndpoints(entries)
+	sortEndpoints(expected)
+
+	for i := range entries {
+		if entries[i].DNSName != expected[i].DNSName || entries[i].Target != expected[i].Target || entries[i].RecordType != expected[i].RecordType {
+			t.Errorf("expected %v, got %v", expected[i], entries[i])
+		}
+	}
+}
+
+func sortEndpoints(endpoints []*endpoint.Endpoint) {
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].DNSName != endpoints[j].DNSName {
+			return endpoints[i].DNSName < endpoints[j].DNSName
+		}
+		if endpoints[i].RecordType != endpoints[j].RecordType {
+			return endpoints[i].RecordType < endpoints[j].RecordType
+		}
+		return endpoints[i].Target < endpoints[j].Target
+	})
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nameserver
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// dnsMessageContentType is the RFC 8484 media type for a wire-format DNS
+// message carried over HTTP.
+const dnsMessageContentType = "application/dns-message"
+
+// DoHHandler returns an http.Handler implementing RFC 8484 DNS-over-HTTPS,
+// answering queries from the same in-memory zone view served by the UDP
+// and TCP listeners. It's meant to be registered at /dns-query on the
+// metrics (or a dedicated) HTTP server.
+func (p *Provider) DoHHandler() http.Handler {
+	return http.HandlerFunc(p.serveDoH)
+}
+
+func (p *Provider) serveDoH(w http.ResponseWriter, req *http.Request) {
+	query, err := readDoHQuery(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reply, err := p.Answer(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	packed, err := reply.Pack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", dnsMessageContentType)
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", minTTL(reply, p.defaultTTL)))
+	w.Write(packed)
+}
+
+// readDoHQuery decodes a DNS query from a DoH GET (base64url `dns=` query
+// parameter) or POST (raw wire-format body) request.
+func readDoHQuery(req *http.Request) (*dns.Msg, error) {
+	var raw []byte
+
+	switch req.Method {
+	case http.MethodGet:
+		encoded := req.URL.Query().Get("dns")
+		if encoded == "" {
+			return nil, fmt.Errorf("missing dns query parameter")
+		}
+		decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dns query parameter: %v", err)
+		}
+		raw = decoded
+	case http.MethodPost:
+		if ct := req.Header.Get("Content-Type"); ct != dnsMessageContentType {
+			return nil, fmt.Errorf("unsupported content type %q", ct)
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %v", err)
+		}
+		raw = body
+	default:
+		return nil, fmt.Errorf("unsupported method %q", req.Method)
+	}
+
+	msg := new(dns.Msg)
+	if err := msg.Unpack(raw); err != nil {
+		return nil, fmt.Errorf("failed to unpack dns message: %v", err)
+	}
+	return msg, nil
+}
+
+// minTTL returns the smallest TTL, in seconds, among a reply's answer
+// records, falling back to defaultTTL when the answer is empty.
+func minTTL(reply *dns.Msg, defaultTTL time.Duration) uint32 {
+	if len(reply.Answer) == 0 {
+		return uint32(defaultTTL / time.Second)
+	}
+	min := reply.Answer[0].Header().Ttl
+	for _, rr := range reply.Answer[1:] {
+		if ttl := rr.Header().Ttl; ttl < min {
+			min = ttl
+		}
+	}
+	return min
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nameserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestReadDoHQuery(t *testing.T) {
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn("foo.example.org"), dns.TypeA)
+	packed, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GET request with a base64url dns parameter", func(t *testing.T) {
+		encoded := base64.RawURLEncoding.EncodeToString(packed)
+		req := httptest.NewRequest(http.MethodGet, "/dns-query?dns="+encoded, nil)
+
+		got, err := readDoHQuery(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Question[0].Name != dns.Fqdn("foo.example.org") {
+			t.Fatalf("unexpected question: %v", got.Question)
+		}
+	})
+
+	t.Run("GET request without a dns parameter is rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/dns-query", nil)
+		if _, err := readDoHQuery(req); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("POST request with a wire-format body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/dns-query", bytes.NewReader(packed))
+		req.Header.Set("Content-Type", dnsMessageContentType)
+
+		got, err := readDoHQuery(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Question[0].Name != dns.Fqdn("foo.example.org") {
+			t.Fatalf("unexpected question: %v", got.Question)
+		}
+	})
+
+	t.Run("POST request with the wrong content type is rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/dns-query", bytes.NewReader(packed))
+		req.Header.Set("Content-Type", "text/plain")
+		if _, err := readDoHQuery(req); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestMinTTL(t *testing.T) {
+	reply := new(dns.Msg)
+	if ttl := minTTL(reply, 5*time.Minute); ttl != 300 {
+		t.Fatalf("expected the default ttl of 300, got %d", ttl)
+	}
+
+	reply.Answer = []dns.RR{
+		&dns.A{Hdr: dns.RR_Header{Ttl: 120}},
+		&dns.A{Hdr: dns.RR_Header{Ttl: 60}},
+	}
+	if ttl := minTTL(reply, 5*time.Minute); ttl != 60 {
+		t.Fatalf("expected the min ttl of 60, got %d", ttl)
+	}
+}
@@ -0,0 +1,302 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nameserver implements a provider.Provider that doubles as an
+// authoritative DNS nameserver for the zones it manages, so that clusters
+// which don't want to depend on an external DNS API can serve the records
+// external-dns plans directly.
+package nameserver
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kubernetes-incubator/external-dns/endpoint"
+	"github.com/kubernetes-incubator/external-dns/plan"
+	"github.com/kubernetes-incubator/external-dns/provider"
+)
+
+// DefaultTTL is used for records that don't carry an explicit TTL.
+const DefaultTTL = 300 * time.Second
+
+// recordKey identifies the records served for a single name and query type.
+type recordKey struct {
+	name       string
+	recordType uint16
+}
+
+// zoneView is an immutable snapshot of the records served by the
+// nameserver. A new zoneView is built and swapped in atomically whenever
+// ApplyChanges is called, so that concurrent queries always see a
+// consistent set of records.
+type zoneView struct {
+	records map[recordKey][]dns.RR
+}
+
+// Provider is a provider.Provider that keeps an in-memory copy of the
+// records it's asked to apply and answers DNS queries for them directly,
+// instead of pushing them to an external DNS API.
+type Provider struct {
+	domainFilter provider.DomainFilter
+	bindAddr     string
+	defaultTTL   time.Duration
+
+	mu   sync.RWMutex
+	view *zoneView
+
+	udp *dns.Server
+	tcp *dns.Server
+}
+
+// NewProvider creates a new nameserver Provider that will serve the zones
+// matched by domainFilter on bindAddr, once Start is called.
+func NewProvider(domainFilter provider.DomainFilter, bindAddr string, defaultTTL time.Duration) *Provider {
+	if defaultTTL <= 0 {
+		defaultTTL = DefaultTTL
+	}
+	return &Provider{
+		domainFilter: domainFilter,
+		bindAddr:     bindAddr,
+		defaultTTL:   defaultTTL,
+		view:         &zoneView{records: map[recordKey][]dns.RR{}},
+	}
+}
+
+// Records returns the endpoints currently served by the nameserver.
+func (p *Provider) Records(zone string) ([]*endpoint.Endpoint, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var endpoints []*endpoint.Endpoint
+	for key, rrs := range p.view.records {
+		name := strings.TrimSuffix(key.name, ".")
+		for _, rr := range rrs {
+			if ep := rrToEndpoint(name, rr); ep != nil {
+				endpoints = append(endpoints, ep)
+			}
+		}
+	}
+	return endpoints, nil
+}
+
+// ApplyChanges atomically swaps the in-memory zone with one reflecting the
+// given changes, so that a query is always answered from a single
+// consistent snapshot.
+func (p *Provider) ApplyChanges(changes *plan.Changes) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	records := cloneRecords(p.view.records)
+
+	for _, ep := range changes.Delete {
+		removeEndpoint(records, ep)
+	}
+	for _, ep := range changes.UpdateOld {
+		removeEndpoint(records, ep)
+	}
+	for _, ep := range changes.Create {
+		addEndpoint(records, ep, p.defaultTTL)
+	}
+	for _, ep := range changes.UpdateNew {
+		addEndpoint(records, ep, p.defaultTTL)
+	}
+
+	p.view = &zoneView{records: records}
+	return nil
+}
+
+// Start starts the UDP and TCP listeners answering queries from the
+// current zone snapshot. It blocks until a listener fails or Shutdown is
+// called.
+func (p *Provider) Start() error {
+	mux := dns.NewServeMux()
+	mux.HandleFunc(".", p.handleQuery)
+
+	p.udp = &dns.Server{Addr: p.bindAddr, Net: "udp", Handler: mux}
+	p.tcp = &dns.Server{Addr: p.bindAddr, Net: "tcp", Handler: mux}
+
+	errs := make(chan error, 2)
+	go func() { errs <- p.udp.ListenAndServe() }()
+	go func() { errs <- p.tcp.ListenAndServe() }()
+
+	return <-errs
+}
+
+// Shutdown gracefully stops the UDP and TCP listeners.
+func (p *Provider) Shutdown() error {
+	if p.udp != nil {
+		if err := p.udp.Shutdown(); err != nil {
+			return err
+		}
+	}
+	if p.tcp != nil {
+		return p.tcp.Shutdown()
+	}
+	return nil
+}
+
+// handleQuery answers a single query received over UDP or TCP.
+func (p *Provider) handleQuery(w dns.ResponseWriter, r *dns.Msg) {
+	reply, err := p.Answer(r)
+	if err != nil {
+		log.Warnf("nameserver: failed to answer query: %v", err)
+		reply = new(dns.Msg)
+		reply.SetRcode(r, dns.RcodeServerFailure)
+	}
+	if err := w.WriteMsg(reply); err != nil {
+		log.Warnf("nameserver: failed to write response: %v", err)
+	}
+}
+
+// Answer builds the reply for a query against the current zone snapshot.
+// It's exported so that other entry points, such as the DNS-over-HTTPS
+// handler, can answer from the same in-memory view.
+func (p *Provider) Answer(r *dns.Msg) (*dns.Msg, error) {
+	if len(r.Question) != 1 {
+		return nil, fmt.Errorf("expected exactly one question, got %d", len(r.Question))
+	}
+	q := r.Question[0]
+
+	reply := new(dns.Msg)
+	reply.SetReply(r)
+	reply.Authoritative = true
+
+	if !p.domainFilter.Match(q.Name) {
+		reply.Rcode = dns.RcodeRefused
+		return reply, nil
+	}
+
+	name := strings.ToLower(q.Name)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	rrs := p.view.records[recordKey{name: name, recordType: q.Qtype}]
+	if len(rrs) == 0 && q.Qtype != dns.TypeCNAME {
+		// Fall back to a CNAME at the name regardless of the queried type,
+		// instead of reporting an empty answer. Only the CNAME record itself
+		// is returned; its target is not looked up.
+		rrs = p.view.records[recordKey{name: name, recordType: dns.TypeCNAME}]
+	}
+
+	reply.Answer = append(reply.Answer, rrs...)
+	if len(rrs) == 0 && !p.nameExistsLocked(name) {
+		reply.Rcode = dns.RcodeNameError
+	}
+	return reply, nil
+}
+
+// nameExistsLocked reports whether the zone carries a record for name
+// under any type. The caller must hold p.mu for reading; it's used to tell
+// apart NXDOMAIN (the name doesn't exist at all) from a NOERROR reply with
+// an empty answer section (the name exists, just not for the queried
+// type).
+func (p *Provider) nameExistsLocked(name string) bool {
+	for key := range p.view.records {
+		if key.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// qtypeFor maps an endpoint record type to the dns query type it's served
+// under; ok is false for record types the nameserver doesn't support.
+func qtypeFor(recordType string) (qtype uint16, ok bool) {
+	switch recordType {
+	case endpoint.RecordTypeA:
+		return dns.TypeA, true
+	case endpoint.RecordTypeAAAA:
+		return dns.TypeAAAA, true
+	case endpoint.RecordTypeCNAME:
+		return dns.TypeCNAME, true
+	case endpoint.RecordTypeTXT:
+		return dns.TypeTXT, true
+	default:
+		return 0, false
+	}
+}
+
+func addEndpoint(records map[recordKey][]dns.RR, ep *endpoint.Endpoint, defaultTTL time.Duration) {
+	qtype, ok := qtypeFor(ep.RecordType)
+	if !ok {
+		log.Debugf("nameserver: ignoring unsupported record type %s for %s", ep.RecordType, ep.DNSName)
+		return
+	}
+
+	ttl := uint32(defaultTTL.Seconds())
+	if ep.RecordTTL.IsConfigured() {
+		ttl = uint32(ep.RecordTTL)
+	}
+
+	name := dns.Fqdn(ep.DNSName)
+	hdr := dns.RR_Header{Name: name, Rrtype: qtype, Class: dns.ClassINET, Ttl: ttl}
+
+	var rr dns.RR
+	switch qtype {
+	case dns.TypeA:
+		rr = &dns.A{Hdr: hdr, A: net.ParseIP(ep.Target)}
+	case dns.TypeAAAA:
+		rr = &dns.AAAA{Hdr: hdr, AAAA: net.ParseIP(ep.Target)}
+	case dns.TypeCNAME:
+		rr = &dns.CNAME{Hdr: hdr, Target: dns.Fqdn(ep.Target)}
+	case dns.TypeTXT:
+		rr = &dns.TXT{Hdr: hdr, Txt: []string{ep.Target}}
+	}
+
+	key := recordKey{name: strings.ToLower(name), recordType: qtype}
+	records[key] = append(records[key], rr)
+}
+
+func removeEndpoint(records map[recordKey][]dns.RR, ep *endpoint.Endpoint) {
+	qtype, ok := qtypeFor(ep.RecordType)
+	if !ok {
+		return
+	}
+	key := recordKey{name: strings.ToLower(dns.Fqdn(ep.DNSName)), recordType: qtype}
+	delete(records, key)
+}
+
+func cloneRecords(records map[recordKey][]dns.RR) map[recordKey][]dns.RR {
+	clone := make(map[recordKey][]dns.RR, len(records))
+	for key, rrs := range records {
+		clone[key] = append([]dns.RR(nil), rrs...)
+	}
+	return clone
+}
+
+func rrToEndpoint(name string, rr dns.RR) *endpoint.Endpoint {
+	ttl := endpoint.TTL(rr.Header().Ttl)
+
+	switch v := rr.(type) {
+	case *dns.A:
+		return endpoint.NewEndpointWithTTL(name, endpoint.RecordTypeA, ttl, v.A.String())
+	case *dns.AAAA:
+		return endpoint.NewEndpointWithTTL(name, endpoint.RecordTypeAAAA, ttl, v.AAAA.String())
+	case *dns.CNAME:
+		return endpoint.NewEndpointWithTTL(name, endpoint.RecordTypeCNAME, ttl, strings.TrimSuffix(v.Target, "."))
+	case *dns.TXT:
+		return endpoint.NewEndpointWithTTL(name, endpoint.RecordTypeTXT, ttl, strings.Join(v.Txt, ""))
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"net"
+	"strings"
+
+	"github.com/kubernetes-incubator/external-dns/endpoint"
+	"github.com/kubernetes-incubator/external-dns/provider"
+)
+
+// FilterPolicy determines how a filterSource treats endpoints that aren't
+// matched by an ignore or allow rule.
+type FilterPolicy string
+
+const (
+	// FilterPolicyAccept lets endpoints through unless they match an ignore
+	// rule. This is the default, backward compatible behaviour.
+	FilterPolicyAccept FilterPolicy = "accept"
+	// FilterPolicyDeny drops every endpoint unless it matches an allow rule.
+	FilterPolicyDeny FilterPolicy = "deny"
+)
+
+// filterSource is a Source that filters the endpoints of another Source.
+type filterSource struct {
+	domainFilter provider.DomainFilter
+	cidrIgnore   []*net.IPNet
+	dnsIgnore    []string
+	cidrAllow    []*net.IPNet
+	dnsAllow     []string
+	policy       FilterPolicy
+	source       Source
+}
+
+// NewFilterSource creates a new filterSource wrapping source, dropping any
+// endpoint outside domainFilter or matching a cidrIgnore/dnsIgnore rule.
+func NewFilterSource(domainFilter provider.DomainFilter, cidrIgnore []*net.IPNet, dnsIgnore []string, source Source) Source {
+	return NewFilteredSource(domainFilter, cidrIgnore, dnsIgnore, nil, nil, FilterPolicyAccept, source)
+}
+
+// NewFilteredSource creates a new filterSource wrapping source, with full
+// control over the allow/deny policy. Endpoints outside domainFilter are
+// always dropped. With FilterPolicyAccept, an endpoint is further dropped
+// if it matches a cidrIgnore/dnsIgnore rule; cidrAllow/dnsAllow are not
+// consulted. With FilterPolicyDeny, an endpoint is kept only if its target
+// matches a cidrAllow entry or its DNS name matches a dnsAllow pattern,
+// which also overrides a matching cidrIgnore/dnsIgnore rule.
+func NewFilteredSource(domainFilter provider.DomainFilter, cidrIgnore []*net.IPNet, dnsIgnore []string, cidrAllow []*net.IPNet, dnsAllow []string, policy FilterPolicy, source Source) Source {
+	if policy == "" {
+		policy = FilterPolicyAccept
+	}
+	return &filterSource{
+		domainFilter: domainFilter,
+		cidrIgnore:   cidrIgnore,
+		dnsIgnore:    dnsIgnore,
+		cidrAllow:    cidrAllow,
+		dnsAllow:     dnsAllow,
+		policy:       policy,
+		source:       source,
+	}
+}
+
+// Endpoints returns endpoints from the wrapped source, filtered according
+// to the domain filter, the ignore rules and, in deny mode, the allow
+// rules.
+func (f *filterSource) Endpoints() ([]*endpoint.Endpoint, error) {
+	endpoints, err := f.source.Endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*endpoint.Endpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if !f.domainFilter.Match(ep.DNSName) {
+			continue
+		}
+
+		if f.policy == FilterPolicyDeny {
+			// In deny mode, membership is derived from the allow-lists
+			// alone: an allow match is authoritative and, within this
+			// policy, also overrides an otherwise matching ignore rule, so
+			// that --cidr-allow/--dns-allow can rescue specific addresses
+			// or names out of a broader --cidr-ignore/--dns-ignore range.
+			if !matchesCidr(ep, f.cidrAllow) && !matchesDNSName(ep.DNSName, f.dnsAllow) {
+				continue
+			}
+		} else if matchesCidr(ep, f.cidrIgnore) || matchesDNSName(ep.DNSName, f.dnsIgnore) {
+			continue
+		}
+
+		filtered = append(filtered, ep)
+	}
+
+	return filtered, nil
+}
+
+// matchesCidr reports whether ep is an A record whose target falls inside
+// one of the given networks.
+func matchesCidr(ep *endpoint.Endpoint, networks []*net.IPNet) bool {
+	if len(networks) == 0 || ep.RecordType != endpoint.RecordTypeA {
+		return false
+	}
+	ip := net.ParseIP(ep.Target)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesDNSName reports whether name matches one of the given patterns. A
+// pattern may be a plain DNS name or carry a single leading wildcard label,
+// e.g. "*.example.com".
+func matchesDNSName(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matchesPattern(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPattern reports whether name matches pattern, expanding a single
+// leading "*." label in pattern into any one non-wildcard label of name.
+func matchesPattern(name, pattern string) bool {
+	if !strings.HasPrefix(pattern, "*.") {
+		return name == pattern
+	}
+
+	suffix := pattern[1:] // keep the leading dot
+	if !strings.HasSuffix(name, suffix) {
+		return false
+	}
+
+	prefix := strings.TrimSuffix(name, suffix)
+	return prefix != "" && !strings.ContainsAny(prefix, ".*")
+}
@@ -31,6 +31,7 @@ var _ Source = &filterSource{}
 func TestFilter(t *testing.T) {
 	t.Run("Filter Cidr Endpoints", testFilterCidrEndpoints)
 	t.Run("Filter DNS Names", testFilterDNSNames)
+	t.Run("Filter Policy", testFilterPolicy)
 }
 
 // testFilterCidrEndpoints tests that filtered IPs from the wrapped source are removed.
@@ -253,3 +254,85 @@ func testFilterBaseDomain(t *testing.T) {
 		})
 	}
 }
+
+// testFilterPolicy tests the accept/deny filter policy and the cidr-allow/
+// dns-allow rescue lists.
+func testFilterPolicy(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{
+		{DNSName: "foo.example.org", Target: "1.2.3.4", RecordType: endpoint.RecordTypeA},
+		{DNSName: "bar.example.org", Target: "192.168.100.10", RecordType: endpoint.RecordTypeA},
+	}
+
+	_, allowedCidr, _ := net.ParseCIDR("192.168.100.0/24")
+
+	for _, tc := range []struct {
+		title      string
+		cidrIgnore []*net.IPNet
+		dnsIgnore  []string
+		cidrAllow  []*net.IPNet
+		dnsAllow   []string
+		policy     FilterPolicy
+		expected   []*endpoint.Endpoint
+	}{
+		{
+			"accept policy with empty allow-lists is unaffected",
+			nil, nil, nil, nil,
+			FilterPolicyAccept,
+			endpoints,
+		},
+		{
+			"deny policy with empty allow-lists drops everything",
+			nil, nil, nil, nil,
+			FilterPolicyDeny,
+			nil,
+		},
+		{
+			"deny policy lets through an entry matching cidr-allow",
+			nil, nil, []*net.IPNet{allowedCidr}, nil,
+			FilterPolicyDeny,
+			[]*endpoint.Endpoint{
+				{DNSName: "bar.example.org", Target: "192.168.100.10", RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			"deny policy lets through an entry matching dns-allow",
+			nil, nil, nil, []string{"foo.example.org"},
+			FilterPolicyDeny,
+			[]*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Target: "1.2.3.4", RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			"deny policy lets an allow match override a matching ignore rule",
+			[]*net.IPNet{allowedCidr}, nil, []*net.IPNet{allowedCidr}, nil,
+			FilterPolicyDeny,
+			[]*endpoint.Endpoint{
+				{DNSName: "bar.example.org", Target: "192.168.100.10", RecordType: endpoint.RecordTypeA},
+			},
+		},
+		{
+			"accept policy still drops an ignored entry even if it's also allow-listed",
+			[]*net.IPNet{allowedCidr}, nil, []*net.IPNet{allowedCidr}, nil,
+			FilterPolicyAccept,
+			[]*endpoint.Endpoint{
+				{DNSName: "foo.example.org", Target: "1.2.3.4", RecordType: endpoint.RecordTypeA},
+			},
+		},
+	} {
+		t.Run(tc.title, func(t *testing.T) {
+			mockSource := new(testutils.MockSource)
+			mockSource.On("Endpoints").Return(endpoints, nil)
+
+			source := NewFilteredSource(provider.NewDomainFilter([]string{}), tc.cidrIgnore, tc.dnsIgnore, tc.cidrAllow, tc.dnsAllow, tc.policy, mockSource)
+
+			got, err := source.Endpoints()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			validateEndpoints(t, got, tc.expected)
+
+			mockSource.AssertExpectations(t)
+		})
+	}
+}